@@ -0,0 +1,276 @@
+package openfigi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxJobsPerRequest is the number of mapping jobs the OpenFIGI API accepts
+// in a single POST body. Batch transparently splits larger inputs into
+// multiple requests of at most this size.
+const maxJobsPerRequest = 10
+
+// defaultConcurrency is the number of chunks a Batch submits in parallel
+// when it has to split a large batch into multiple requests.
+const defaultConcurrency = 4
+
+// BatchResult is the outcome of a single job submitted through a Batch.
+// Exactly one of Data or Err is set; Warning is set when OpenFIGI returned
+// a warning alongside a successful mapping.
+type BatchResult struct {
+	Data    []*FIGI
+	Warning string
+	Err     error
+}
+
+// mappingResponseItem mirrors a single element of the JSON array OpenFIGI
+// returns from /v1/mapping.
+type mappingResponseItem struct {
+	Data    []*FIGI `json:"data,omitempty"`
+	Error   string  `json:"error,omitempty"`
+	Warning string  `json:"warning,omitempty"`
+}
+
+type batchJob struct {
+	req *FIGIRequest
+	err error // set when the job failed validation before it could be queued
+}
+
+// Batch collects multiple identifier lookups and submits them to OpenFIGI
+// in as few HTTP requests as possible. Jobs above the API's per-request
+// cap are split into chunks which are submitted concurrently by a bounded
+// worker pool. Results are returned aligned with the order jobs were
+// added, and a failure in one job never fails the others.
+type Batch struct {
+	jobs        []*batchJob
+	apiKey      string
+	timeout     time.Duration
+	concurrency int
+	limiter     *RateLimiter
+	retry       RetryPolicy
+	cache       Cache
+}
+
+// NewBatch creates an empty Batch.
+func NewBatch() *Batch {
+	return &Batch{timeout: defaultTimeout, concurrency: defaultConcurrency, retry: DefaultRetryPolicy}
+}
+
+// Add queues an identifier lookup for the batch and returns the Batch so
+// calls can be chained, e.g. NewBatch().Add(...).Add(...).
+func (b *Batch) Add(idtype, idvalue string, opts ...func(*FIGIRequest)) *Batch {
+	if !isValidIdentifier(idtype) {
+		b.jobs = append(b.jobs, &batchJob{err: ErrNotValidIdentifier})
+		return b
+	}
+	fr := &FIGIRequest{IDType: idtype, IDValue: idvalue, timeout: b.timeout}
+	for _, opt := range opts {
+		opt(fr)
+	}
+	b.jobs = append(b.jobs, &batchJob{req: fr})
+	return b
+}
+
+// APIKey sets the API key used for all jobs in the batch.
+func (b *Batch) APIKey(key string) *Batch {
+	b.apiKey = key
+	return b
+}
+
+// Concurrency sets how many chunks the batch submits in parallel once it
+// has to split into more than one request. The default is 4.
+func (b *Batch) Concurrency(n int) *Batch {
+	b.concurrency = n
+	return b
+}
+
+// RateLimit caps the batch to requestsPerMinute requests and
+// jobsPerMinute mapping jobs per minute, accounting for the number of
+// jobs each chunk is about to submit. Without this, the batch defaults
+// to OpenFIGI's documented free-tier or keyed-tier quota depending on
+// whether APIKey is set.
+func (b *Batch) RateLimit(requestsPerMinute, jobsPerMinute int) *Batch {
+	b.limiter = NewRateLimiter(requestsPerMinute, jobsPerMinute)
+	return b
+}
+
+// Retry overrides the RetryPolicy used for chunks submitted by this
+// batch, taking precedence over DefaultRetryPolicy.
+func (b *Batch) Retry(policy RetryPolicy) *Batch {
+	b.retry = policy
+	return b
+}
+
+// WithCache sets the Cache used to store and look up each job's result.
+// A job is looked up under the same key FIGIRequest.Do would use for it,
+// so a batch and a standalone request for the same job share cache
+// entries. Without this, the batch falls back to the package-wide
+// defaultCache set by RedisCache, same as DefaultClient.
+func (b *Batch) WithCache(c Cache) *Batch {
+	b.cache = c
+	return b
+}
+
+// WithExchange is an Add option that sets the ExchangeCode on the job's
+// underlying FIGIRequest.
+func WithExchange(exch string) func(*FIGIRequest) {
+	return func(fr *FIGIRequest) {
+		fr.ExchangeCode = exch
+	}
+}
+
+// Do submits the batch and returns one BatchResult per job, in the order
+// jobs were added.
+func (b *Batch) Do() ([]BatchResult, error) {
+	return b.DoContext(context.Background())
+}
+
+// DoContext is like Do but honors ctx cancellation and deadlines.
+func (b *Batch) DoContext(ctx context.Context) ([]BatchResult, error) {
+	results := make([]BatchResult, len(b.jobs))
+
+	var toSend []int
+	for i, j := range b.jobs {
+		if j.err != nil {
+			results[i] = BatchResult{Err: j.err}
+			continue
+		}
+		toSend = append(toSend, i)
+	}
+
+	concurrency := b.concurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+	limiter := b.limiter
+	if limiter == nil {
+		limiter = defaultRateLimiter(b.apiKey)
+	}
+
+	chunks := chunkIndices(toSend, maxJobsPerRequest)
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, chunk := range chunks {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(chunk []int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			b.doChunk(ctx, chunk, results, limiter)
+		}(chunk)
+	}
+	wg.Wait()
+
+	return results, nil
+}
+
+// doChunk submits the jobs at the given indices as a single HTTP request
+// and stores their outcome in results. It shares doMappingWithRetry with
+// Client.do, so a chunk gets the same APIError wrapping and backoff-and-
+// retry a single FIGIRequest.Do/DoContext would, and checks/populates the
+// same cache, keyed per job so entries are shared with FIGIRequest.Do.
+func (b *Batch) doChunk(ctx context.Context, idxs []int, results []BatchResult, limiter *RateLimiter) {
+	cache := b.cache
+	if cache == nil {
+		cache = defaultCache
+	}
+
+	toPost := idxs
+	if cache != nil {
+		toPost = nil
+		for _, idx := range idxs {
+			key, err := mappingCacheKey(b.jobs[idx].req)
+			if err != nil {
+				results[idx] = BatchResult{Err: err}
+				continue
+			}
+			data, found, err := cache.Get(ctx, key)
+			if err != nil {
+				results[idx] = BatchResult{Err: err}
+				continue
+			}
+			if !found {
+				toPost = append(toPost, idx)
+				continue
+			}
+			if data == nil {
+				results[idx] = BatchResult{Err: ErrNoIdentifierFound}
+			} else {
+				results[idx] = BatchResult{Data: data}
+			}
+		}
+	}
+	if len(toPost) == 0 {
+		return
+	}
+
+	reqdata := make([]*FIGIRequest, len(toPost))
+	for i, idx := range toPost {
+		reqdata[i] = b.jobs[idx].req
+	}
+
+	httpClient := &http.Client{Timeout: b.timeout}
+	items, err := doMappingWithRetry(ctx, httpClient, b.apiKey, b.retry, limiter, nil, reqdata)
+	if err != nil {
+		fillErr(results, toPost, err)
+		return
+	}
+
+	for i, idx := range toPost {
+		if i >= len(items) {
+			results[idx] = BatchResult{Err: ErrAPIError}
+			continue
+		}
+		switch item := items[i]; {
+		case item.Error == "No identifier found.":
+			results[idx] = BatchResult{Err: ErrNoIdentifierFound}
+			cacheJob(ctx, cache, b.jobs[idx].req, nil)
+		case item.Error != "":
+			results[idx] = BatchResult{Err: fmt.Errorf(item.Error)}
+		default:
+			results[idx] = BatchResult{Data: item.Data, Warning: item.Warning}
+			cacheJob(ctx, cache, b.jobs[idx].req, item.Data)
+		}
+	}
+}
+
+// cacheJob stores data (nil for a negative/no-identifier-found result)
+// under req's mappingCacheKey, at defaultCacheTTL. It's a no-op if cache
+// is nil, and swallows a Set error since a batch job otherwise succeeded.
+func cacheJob(ctx context.Context, cache Cache, req *FIGIRequest, data []*FIGI) {
+	if cache == nil {
+		return
+	}
+	key, err := mappingCacheKey(req)
+	if err != nil {
+		return
+	}
+	_ = cache.Set(ctx, key, data, defaultCacheTTL)
+}
+
+func fillErr(results []BatchResult, idxs []int, err error) {
+	for _, idx := range idxs {
+		results[idx] = BatchResult{Err: err}
+	}
+}
+
+// chunkIndices splits idxs into slices of at most size elements each,
+// preserving order.
+func chunkIndices(idxs []int, size int) [][]int {
+	if len(idxs) == 0 {
+		return nil
+	}
+	var chunks [][]int
+	for len(idxs) > 0 {
+		n := size
+		if n > len(idxs) {
+			n = len(idxs)
+		}
+		chunks = append(chunks, idxs[:n])
+		idxs = idxs[n:]
+	}
+	return chunks
+}
@@ -0,0 +1,24 @@
+package openfigi
+
+import "testing"
+
+func TestChunkIndices(t *testing.T) {
+	chunks := chunkIndices([]int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}, 10)
+	if len(chunks) != 2 {
+		t.Fatalf("expected 2 chunks, got %d", len(chunks))
+	}
+	if len(chunks[0]) != 10 || len(chunks[1]) != 2 {
+		t.Fatalf("unexpected chunk sizes: %v", chunks)
+	}
+}
+
+func TestBatchAddInvalidIdentifier(t *testing.T) {
+	b := NewBatch().Add("NOT_A_REAL_TYPE", "X")
+	results, err := b.Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Err != ErrNotValidIdentifier {
+		t.Fatalf("expected ErrNotValidIdentifier, got %+v", results)
+	}
+}
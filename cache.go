@@ -0,0 +1,264 @@
+package openfigi
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/gomodule/redigo/redis"
+)
+
+// defaultCacheTTL is how long a FIGIRequest's result is kept in the cache
+// when no CacheTTL override is set. A ttl of 0 means no expiry.
+const defaultCacheTTL = 24 * time.Hour
+
+// Cache is the interface used to store and look up FIGI mapping results,
+// keyed by the marshaled request body. Implementations must be safe for
+// concurrent use. A cache hit with a nil data slice represents a cached
+// ErrNoIdentifierFound result, so repeated lookups of a bad identifier
+// don't hit the API again.
+type Cache interface {
+	// Get returns the cached result for key. found is false when key is
+	// not present in the cache.
+	Get(ctx context.Context, key string) (data []*FIGI, found bool, err error)
+	// Set stores data under key for at most ttl. A ttl of 0 means no
+	// expiry. data is nil to cache a negative (no identifier found)
+	// result.
+	Set(ctx context.Context, key string, data []*FIGI, ttl time.Duration) error
+}
+
+// defaultCache is used by DefaultClient, and so by FIGIRequests that
+// don't set their own cache via WithCache. It is nil (no caching) until
+// RedisCache sets it.
+var defaultCache Cache
+
+// RedisCache sets up a single-node Redis instance as the package-wide
+// default cache for openFIGI data. If you don't call this on startup, no
+// caching is performed, unless a request is given its own Cache via
+// WithCache. For HA deployments see NewRedisSentinelCache and
+// NewRedisClusterCache.
+func RedisCache(addr string) error {
+	defaultCache = NewRedigoCache(addr)
+	return nil
+}
+
+// RedigoCache is a Cache backed by a single-node Redis instance via
+// github.com/gomodule/redigo. It is the implementation RedisCache sets up
+// as the package-wide default.
+type RedigoCache struct {
+	pool   *redis.Pool
+	prefix string
+}
+
+// NewRedigoCache creates a RedigoCache connected to the Redis instance at
+// addr.
+func NewRedigoCache(addr string) *RedigoCache {
+	return &RedigoCache{
+		pool: &redis.Pool{
+			MaxIdle:     5,
+			IdleTimeout: 240 * time.Second,
+			Dial: func() (redis.Conn, error) {
+				return redis.Dial("tcp", addr)
+			},
+		},
+	}
+}
+
+// Namespace sets a key prefix applied to every key this cache reads or
+// writes, so it can share a Redis instance with other applications.
+func (c *RedigoCache) Namespace(prefix string) *RedigoCache {
+	c.prefix = prefix
+	return c
+}
+
+// Get implements Cache.
+func (c *RedigoCache) Get(ctx context.Context, key string) ([]*FIGI, bool, error) {
+	conn, err := c.pool.GetContext(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer conn.Close() // nolint: errcheck
+
+	res, err := conn.Do("GET", c.prefix+key)
+	if err != nil {
+		return nil, false, err
+	}
+	if res == nil {
+		return nil, false, nil
+	}
+	b, err := redis.Bytes(res, err)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(b) == 0 {
+		return nil, true, nil
+	}
+	data := []*FIGI{}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// Set implements Cache.
+func (c *RedigoCache) Set(ctx context.Context, key string, data []*FIGI, ttl time.Duration) error {
+	conn, err := c.pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close() // nolint: errcheck
+
+	var js []byte
+	if data != nil {
+		js, err = json.Marshal(data)
+		if err != nil {
+			return err
+		}
+	}
+	if ttl > 0 {
+		_, err = conn.Do("SET", c.prefix+key, js, "EX", int(ttl.Seconds()))
+		return err
+	}
+	_, err = conn.Do("SET", c.prefix+key, js)
+	return err
+}
+
+// RedisV8Cache is a Cache backed by github.com/go-redis/redis/v8, which
+// supports Sentinel and Cluster deployments in addition to a single node.
+type RedisV8Cache struct {
+	client goredis.UniversalClient
+	prefix string
+}
+
+// NewRedisV8Cache wraps an already constructed go-redis client, e.g. one
+// built with goredis.NewClient for a single node.
+func NewRedisV8Cache(client goredis.UniversalClient) *RedisV8Cache {
+	return &RedisV8Cache{client: client}
+}
+
+// NewRedisSentinelCache creates a RedisV8Cache backed by a Sentinel
+// managed Redis deployment. Set opts.TLSConfig to connect over TLS.
+func NewRedisSentinelCache(opts *goredis.FailoverOptions) *RedisV8Cache {
+	return &RedisV8Cache{client: goredis.NewFailoverClient(opts)}
+}
+
+// NewRedisClusterCache creates a RedisV8Cache backed by a Redis Cluster.
+// Set opts.TLSConfig to connect over TLS.
+func NewRedisClusterCache(opts *goredis.ClusterOptions) *RedisV8Cache {
+	return &RedisV8Cache{client: goredis.NewClusterClient(opts)}
+}
+
+// Namespace sets a key prefix applied to every key this cache reads or
+// writes, so it can share a Redis deployment with other applications.
+func (c *RedisV8Cache) Namespace(prefix string) *RedisV8Cache {
+	c.prefix = prefix
+	return c
+}
+
+// Get implements Cache.
+func (c *RedisV8Cache) Get(ctx context.Context, key string) ([]*FIGI, bool, error) {
+	b, err := c.client.Get(ctx, c.prefix+key).Bytes()
+	if err == goredis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if len(b) == 0 {
+		return nil, true, nil
+	}
+	data := []*FIGI{}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// Set implements Cache.
+func (c *RedisV8Cache) Set(ctx context.Context, key string, data []*FIGI, ttl time.Duration) error {
+	var js []byte
+	if data != nil {
+		var err error
+		js, err = json.Marshal(data)
+		if err != nil {
+			return err
+		}
+	}
+	return c.client.Set(ctx, c.prefix+key, js, ttl).Err()
+}
+
+// MemoryCache is an in-process, fixed-size LRU Cache. It's useful for
+// tests and small deployments that don't want a Redis dependency.
+type MemoryCache struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+}
+
+type memoryCacheEntry struct {
+	key       string
+	data      []*FIGI
+	expiresAt time.Time // zero means no expiry
+}
+
+// NewMemoryCache creates a MemoryCache holding at most capacity entries.
+// A capacity of 0 means unbounded.
+func NewMemoryCache(capacity int) *MemoryCache {
+	return &MemoryCache{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// Get implements Cache.
+func (c *MemoryCache) Get(ctx context.Context, key string) ([]*FIGI, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := el.Value.(*memoryCacheEntry)
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		c.ll.Remove(el)
+		delete(c.items, key)
+		return nil, false, nil
+	}
+	c.ll.MoveToFront(el)
+	return entry.data, true, nil
+}
+
+// Set implements Cache.
+func (c *MemoryCache) Set(ctx context.Context, key string, data []*FIGI, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl)
+	}
+	entry := &memoryCacheEntry{key: key, data: data, expiresAt: expiresAt}
+
+	if el, ok := c.items[key]; ok {
+		el.Value = entry
+		c.ll.MoveToFront(el)
+		return nil
+	}
+
+	el := c.ll.PushFront(entry)
+	c.items[key] = el
+	if c.capacity > 0 && c.ll.Len() > c.capacity {
+		oldest := c.ll.Back()
+		if oldest != nil {
+			c.ll.Remove(oldest)
+			delete(c.items, oldest.Value.(*memoryCacheEntry).key)
+		}
+	}
+	return nil
+}
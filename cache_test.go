@@ -0,0 +1,63 @@
+package openfigi
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryCacheRoundTrip(t *testing.T) {
+	c := NewMemoryCache(2)
+	ctx := context.Background()
+
+	if _, found, err := c.Get(ctx, "a"); err != nil || found {
+		t.Fatalf("expected miss, got found=%v err=%v", found, err)
+	}
+
+	want := []*FIGI{{Ticker: "GTT"}}
+	if err := c.Set(ctx, "a", want, 0); err != nil {
+		t.Fatal(err)
+	}
+	got, found, err := c.Get(ctx, "a")
+	if err != nil || !found || got[0].Ticker != "GTT" {
+		t.Fatalf("expected hit with Ticker=GTT, got %+v found=%v err=%v", got, found, err)
+	}
+
+	// A nil value caches a negative (not found) result.
+	if err := c.Set(ctx, "b", nil, 0); err != nil {
+		t.Fatal(err)
+	}
+	got, found, err = c.Get(ctx, "b")
+	if err != nil || !found || got != nil {
+		t.Fatalf("expected negative hit, got %+v found=%v err=%v", got, found, err)
+	}
+}
+
+func TestMemoryCacheEviction(t *testing.T) {
+	c := NewMemoryCache(1)
+	ctx := context.Background()
+
+	_ = c.Set(ctx, "a", []*FIGI{{Ticker: "A"}}, 0)
+	_ = c.Set(ctx, "b", []*FIGI{{Ticker: "B"}}, 0)
+
+	if _, found, _ := c.Get(ctx, "a"); found {
+		t.Fatal("expected a to be evicted")
+	}
+	if _, found, _ := c.Get(ctx, "b"); !found {
+		t.Fatal("expected b to still be cached")
+	}
+}
+
+func TestMemoryCacheExpiry(t *testing.T) {
+	c := NewMemoryCache(0)
+	ctx := context.Background()
+
+	if err := c.Set(ctx, "a", []*FIGI{{Ticker: "A"}}, time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	if _, found, _ := c.Get(ctx, "a"); found {
+		t.Fatal("expected entry to have expired")
+	}
+}
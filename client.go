@@ -0,0 +1,484 @@
+package openfigi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// apiURLV3 is a var, not a const, so tests can point it at an
+// httptest.Server instead of the real API.
+var apiURLV3 = "https://api.openfigi.com/v3"
+
+// Client is an OpenFIGI API client. It holds the configuration shared by
+// every request it makes: API key, HTTP client, cache, and retry policy.
+// The package-level NewRequest/Do API is a thin wrapper around
+// DefaultClient, so most callers never need to construct one directly;
+// use NewClient when you need multiple configurations (e.g. different
+// API keys or caches) in the same process.
+type Client struct {
+	apiKey     string
+	httpClient *http.Client
+	cache      Cache
+	cacheTTL   time.Duration
+	retry      RetryPolicy
+	limiter    *RateLimiter
+	onThrottle func(time.Duration)
+}
+
+// DefaultClient is the Client used by the package-level NewRequest/Do
+// API. It can be reconfigured directly, e.g. DefaultClient = NewClient(WithAPIKey("...")).
+var DefaultClient = NewClient()
+
+// Option configures a Client constructed by NewClient.
+type Option func(*Client)
+
+// NewClient creates a Client, applying opts over the package defaults:
+// no API key, a defaultTimeout http.Client, no cache, and
+// DefaultRetryPolicy. Unless WithRateLimit is given, rate limiting is
+// resolved per request against the shared default*Limiter matching
+// OpenFIGI's documented free-tier or keyed-tier quota for the API key
+// that request actually uses (see Client.rateLimiter).
+func NewClient(opts ...Option) *Client {
+	c := &Client{
+		httpClient: &http.Client{Timeout: defaultTimeout},
+		cacheTTL:   defaultCacheTTL,
+		retry:      DefaultRetryPolicy,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// rateLimiter returns the RateLimiter to use for a request sent with
+// apiKey: c.limiter if WithRateLimit configured one explicitly,
+// otherwise the shared default limiter matching apiKey's tier. It's
+// resolved per call, rather than once in NewClient, so a key set on a
+// per-request basis (FIGIRequest.APIKey, via the back-compat
+// NewRequest/Do API) still selects the keyed-tier limiter even though
+// c.apiKey itself may be empty.
+func (c *Client) rateLimiter(apiKey string) *RateLimiter {
+	if c.limiter != nil {
+		return c.limiter
+	}
+	return defaultRateLimiter(apiKey)
+}
+
+// WithAPIKey sets the API key sent with every request the Client makes.
+// Note that openFIGI works perfectly fine without an API key, but
+// requests are rate limited more aggressively without one.
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.apiKey = key }
+}
+
+// WithHTTPClient overrides the *http.Client used for requests, e.g. to
+// set a custom Timeout or Transport.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithCache sets the Cache a Client uses to store and look up mapping
+// results.
+func WithCache(cache Cache) Option {
+	return func(c *Client) { c.cache = cache }
+}
+
+// WithCacheTTL overrides how long a Client's mapping results are kept in
+// the cache. The default is defaultCacheTTL.
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(c *Client) { c.cacheTTL = ttl }
+}
+
+// WithRetryPolicy overrides the RetryPolicy a Client uses for failed
+// requests. The default is DefaultRetryPolicy.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) { c.retry = policy }
+}
+
+// WithRateLimit overrides the Client's RateLimiter, capping it to
+// requestsPerMinute requests and jobsPerMinute mapping jobs per minute.
+// Without this option, the Client defaults to OpenFIGI's documented
+// free-tier or keyed-tier quota depending on whether WithAPIKey is set.
+func WithRateLimit(requestsPerMinute, jobsPerMinute int) Option {
+	return func(c *Client) { c.limiter = NewRateLimiter(requestsPerMinute, jobsPerMinute) }
+}
+
+// WithOnThrottle sets a callback invoked whenever the Client's
+// RateLimiter has to block a request, with the duration it waited. It
+// can be combined with WithRateLimit in either order.
+func WithOnThrottle(fn func(wait time.Duration)) Option {
+	return func(c *Client) { c.onThrottle = fn }
+}
+
+// Do performs fr using c's configuration. It is equivalent to
+// DoContext(context.Background(), fr).
+func (c *Client) Do(fr *FIGIRequest) ([]*FIGI, error) {
+	return c.do(context.Background(), fr)
+}
+
+// DoContext is like Do but honors ctx cancellation and deadlines.
+func (c *Client) DoContext(ctx context.Context, fr *FIGIRequest) ([]*FIGI, error) {
+	return c.do(ctx, fr)
+}
+
+// do is the shared implementation behind Client.Do/DoContext and
+// FIGIRequest.Do/DoContext. Fields set directly on fr (APIKey, Retry,
+// WithCache, CacheTTL) take precedence over c's configuration.
+func (c *Client) do(ctx context.Context, fr *FIGIRequest) ([]*FIGI, error) {
+	apiKey := fr.apiKey
+	if apiKey == "" {
+		apiKey = c.apiKey
+	}
+	cache := fr.cache
+	if cache == nil {
+		cache = c.cache
+	}
+	if cache == nil {
+		// Fall back to the package-wide default set by RedisCache, so
+		// DefaultClient keeps working for callers who configure caching
+		// that way instead of via WithCache/NewClient.
+		cache = defaultCache
+	}
+	cacheTTL := c.cacheTTL
+	if fr.cacheTTLSet {
+		cacheTTL = fr.cacheTTL
+	}
+	policy := fr.retry
+	if policy == (RetryPolicy{}) {
+		policy = c.retry
+	}
+	httpClient := c.httpClient
+	if fr.timeout != 0 && fr.timeout != defaultTimeout && fr.timeout != httpClient.Timeout {
+		cp := *httpClient
+		cp.Timeout = fr.timeout
+		httpClient = &cp
+	}
+
+	key, err := mappingCacheKey(fr)
+	if err != nil {
+		return nil, err
+	}
+	if cache != nil {
+		data, found, err := cache.Get(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			if data == nil {
+				return nil, ErrNoIdentifierFound
+			}
+			return data, nil
+		}
+	}
+
+	items, err := doMappingWithRetry(ctx, httpClient, apiKey, policy, c.rateLimiter(apiKey), c.onThrottle, []*FIGIRequest{fr})
+	if err != nil {
+		return nil, err
+	}
+
+	switch item := items[0]; {
+	case item.Error == "No identifier found.":
+		if cache != nil {
+			if err := cache.Set(ctx, key, nil, cacheTTL); err != nil {
+				return nil, ErrCacheError
+			}
+		}
+		return nil, ErrNoIdentifierFound
+	case item.Error != "":
+		return nil, fmt.Errorf(item.Error)
+	default:
+		if cache != nil {
+			if err := cache.Set(ctx, key, item.Data, cacheTTL); err != nil {
+				return nil, ErrCacheError
+			}
+		}
+		return item.Data, nil
+	}
+}
+
+// mappingCacheKey returns the cache key for a single-job FIGIRequest: the
+// same JSON body doMappingJobs would submit for it. Batch.doChunk uses
+// this too, so a job resolved by Batch hits the same cache entry a
+// standalone FIGIRequest.Do for the same job would.
+func mappingCacheKey(fr *FIGIRequest) (string, error) {
+	js, err := json.Marshal([]*FIGIRequest{fr})
+	if err != nil {
+		return "", err
+	}
+	return string(js), nil
+}
+
+// doMappingWithRetry submits reqdata to /v1/mapping, retrying on a 429 or
+// 5xx response per policy. It's the shared core behind both Client.do
+// (one job, from FIGIRequest.Do/DoContext) and Batch.doChunk (a chunk of
+// jobs), so both get the same APIError wrapping, backoff-and-retry and
+// rate limiting instead of diverging implementations.
+func doMappingWithRetry(ctx context.Context, httpClient *http.Client, apiKey string, policy RetryPolicy, limiter *RateLimiter, onThrottle func(time.Duration), reqdata []*FIGIRequest) ([]mappingResponseItem, error) {
+	start := time.Now()
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		if err := waitForCapacity(ctx, limiter, len(reqdata), onThrottle); err != nil {
+			return nil, err
+		}
+
+		items, retryAfter, err := doMappingJobs(ctx, httpClient, apiKey, reqdata)
+		if err == nil {
+			return items, nil
+		}
+
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || !isRetryableStatus(apiErr.StatusCode) {
+			return nil, err
+		}
+		lastErr = err
+
+		if attempt >= policy.MaxRetries {
+			return nil, lastErr
+		}
+		wait := policy.nextBackoff(attempt, retryAfter)
+		if policy.MaxElapsed > 0 && time.Since(start)+wait > policy.MaxElapsed {
+			return nil, lastErr
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// doMappingJobs performs a single /v1/mapping HTTP attempt for reqdata (one
+// job, or a Batch chunk of several) and parses the response into one
+// mappingResponseItem per job, in the same order. It returns the
+// Retry-After header value alongside any transport or status error so the
+// caller can factor it into the retry delay.
+func doMappingJobs(ctx context.Context, httpClient *http.Client, apiKey string, reqdata []*FIGIRequest) ([]mappingResponseItem, string, error) {
+	js, err := json.Marshal(reqdata)
+	if err != nil {
+		return nil, "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBuffer(js))
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("X-OPENFIGI-APIKEY", apiKey)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, resp.Header.Get("Retry-After"), &APIError{StatusCode: resp.StatusCode, Body: body}
+	}
+
+	items := []mappingResponseItem{}
+	if err := json.Unmarshal(body, &items); err != nil {
+		return nil, "", err
+	}
+	if len(items) == 0 {
+		return nil, "", ErrAPIError
+	}
+
+	return items, "", nil
+}
+
+// SearchQuery describes a /v3/search or /v3/filter request. Query is
+// required for Search; the remaining fields narrow results the same way
+// they narrow a mapping job. Set Start to SearchResult.Next/FilterResult.Next
+// to fetch the next page.
+type SearchQuery struct {
+	Query        string `json:"query,omitempty"`
+	ExchangeCode string `json:"exchCode,omitempty"`
+	MICCode      string `json:"micCode,omitempty"`
+	Currency     string `json:"currency,omitempty"`
+	MarketSector string `json:"marketSecDes,omitempty"`
+	SecurityType string `json:"securityType,omitempty"`
+	Start        string `json:"start,omitempty"`
+}
+
+// FilterQuery is identical to SearchQuery; /v3/filter accepts the same
+// fields as /v3/search but additionally reports FilterResult.Total.
+type FilterQuery = SearchQuery
+
+// SearchResult is the response to a /v3/search request. Next is set when
+// more results are available; pass it back as SearchQuery.Start to fetch
+// the next page.
+type SearchResult struct {
+	Data  []*FIGI `json:"data"`
+	Next  string  `json:"next,omitempty"`
+	Error string  `json:"error,omitempty"`
+}
+
+// FilterResult is the response to a /v3/filter request.
+type FilterResult struct {
+	Data  []*FIGI `json:"data"`
+	Next  string  `json:"next,omitempty"`
+	Total int     `json:"total"`
+	Error string  `json:"error,omitempty"`
+}
+
+// Search queries /v3/search for instruments matching q.Query, optionally
+// narrowed by the other SearchQuery fields.
+func (c *Client) Search(ctx context.Context, q SearchQuery) (*SearchResult, error) {
+	result := &SearchResult{}
+	if err := c.postJSON(ctx, apiURLV3+"/search", q, result); err != nil {
+		return nil, err
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf(result.Error)
+	}
+	return result, nil
+}
+
+// Filter is like Search but also reports the total number of matches via
+// FilterResult.Total.
+func (c *Client) Filter(ctx context.Context, q FilterQuery) (*FilterResult, error) {
+	result := &FilterResult{}
+	if err := c.postJSON(ctx, apiURLV3+"/filter", q, result); err != nil {
+		return nil, err
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf(result.Error)
+	}
+	return result, nil
+}
+
+// MappingValues enumerates the valid values OpenFIGI accepts for the
+// given FIGIRequest field, e.g. "exchCode", "micCode", "currency" or
+// "marketSecDes".
+func (c *Client) MappingValues(ctx context.Context, key string) ([]string, error) {
+	if err := waitForCapacity(ctx, c.rateLimiter(c.apiKey), 1, c.onThrottle); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURLV3+"/mapping/values/"+key, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-OPENFIGI-APIKEY", c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 200 {
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: body}
+	}
+
+	result := struct {
+		Values []string `json:"values"`
+		Error  string   `json:"error,omitempty"`
+	}{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf(result.Error)
+	}
+	return result.Values, nil
+}
+
+// ValidateFields checks fr's ExchangeCode, MICCode, Currency and
+// MarketSector against the enums MappingValues reports for "exchCode",
+// "micCode", "currency" and "marketSecDes" respectively, skipping any
+// field that's empty. It performs one MappingValues call per non-empty
+// field, so callers doing this often should cache the valid sets
+// themselves.
+func (c *Client) ValidateFields(ctx context.Context, fr *FIGIRequest) error {
+	checks := []struct {
+		key   string
+		value string
+	}{
+		{"exchCode", fr.ExchangeCode},
+		{"micCode", fr.MICCode},
+		{"currency", fr.Currency},
+		{"marketSecDes", fr.MarketSector},
+	}
+	for _, chk := range checks {
+		if chk.value == "" {
+			continue
+		}
+		values, err := c.MappingValues(ctx, chk.key)
+		if err != nil {
+			return err
+		}
+		found := false
+		for _, v := range values {
+			if v == chk.value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("openfigi: %q is not a valid value for %s", chk.value, chk.key)
+		}
+	}
+	return nil
+}
+
+// postJSON POSTs body as JSON to url and decodes the JSON response into
+// out. It does not retry; Search/Filter/MappingValues are exploratory
+// endpoints, unlike the mapping endpoint's retry-aware Do/DoContext.
+func (c *Client) postJSON(ctx context.Context, url string, body, out interface{}) error {
+	if err := waitForCapacity(ctx, c.rateLimiter(c.apiKey), 1, c.onThrottle); err != nil {
+		return err
+	}
+
+	js, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(js))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("X-OPENFIGI-APIKEY", c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != 200 {
+		return &APIError{StatusCode: resp.StatusCode, Body: respBody}
+	}
+
+	return json.Unmarshal(respBody, out)
+}
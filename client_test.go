@@ -0,0 +1,218 @@
+package openfigi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewClientOptions(t *testing.T) {
+	c := NewClient(WithAPIKey("secret"), WithCacheTTL(0))
+	if c.apiKey != "secret" {
+		t.Fatalf("expected apiKey=secret, got %q", c.apiKey)
+	}
+	if c.cacheTTL != 0 {
+		t.Fatalf("expected cacheTTL=0, got %v", c.cacheTTL)
+	}
+	if c.retry != DefaultRetryPolicy {
+		t.Fatalf("expected default retry policy, got %+v", c.retry)
+	}
+}
+
+// withTestAPIURL points the package-level apiURL at srv for the duration
+// of the test, restoring it on cleanup.
+func withTestAPIURL(t *testing.T, srv *httptest.Server) {
+	orig := apiURL
+	apiURL = srv.URL
+	t.Cleanup(func() { apiURL = orig })
+}
+
+func TestClientDoRetriesOn429ThenSucceeds(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"data":[{"figi":"BBG000BLNNH6"}]}]`))
+	}))
+	defer srv.Close()
+	withTestAPIURL(t, srv)
+
+	fr, err := NewRequest("ID_ISIN", "US0378331005")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := fr.DoContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 1 || data[0].FIGI != "BBG000BLNNH6" {
+		t.Fatalf("unexpected data: %+v", data)
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("expected the 429 to be retried once (2 requests), got %d", got)
+	}
+}
+
+// A FIGIRequest with CacheTTL(0) means "cache forever", not "use the
+// Client's default ttl" - the zero value would otherwise be
+// indistinguishable from CacheTTL never having been called.
+func TestClientDoCacheTTLZeroMeansNoExpiry(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"data":[{"figi":"BBG000BLNNH6"}]}]`))
+	}))
+	defer srv.Close()
+	withTestAPIURL(t, srv)
+
+	cache := NewMemoryCache(0)
+	c := NewClient(WithCache(cache), WithCacheTTL(time.Hour))
+
+	fr, err := NewRequest("ID_ISIN", "US0378331005")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fr.CacheTTL(0)
+
+	if _, err := c.DoContext(context.Background(), fr); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := mappingCacheKey(fr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	el, ok := cache.items[key]
+	if !ok {
+		t.Fatal("expected a cache entry")
+	}
+	if entry := el.Value.(*memoryCacheEntry); !entry.expiresAt.IsZero() {
+		t.Fatalf("expected CacheTTL(0) to cache with no expiry, got expiresAt=%v", entry.expiresAt)
+	}
+}
+
+// newV3TestClient points apiURLV3 at an httptest.Server running handler
+// for the duration of the test, and returns a plain Client for it.
+func newV3TestClient(t *testing.T, handler http.Handler) *Client {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	orig := apiURLV3
+	apiURLV3 = srv.URL
+	t.Cleanup(func() { apiURLV3 = orig })
+	return NewClient()
+}
+
+func TestClientSearch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/search", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":[{"figi":"BBG000BLNNH6"}],"next":"abc123"}`))
+	})
+	c := newV3TestClient(t, mux)
+
+	result, err := c.Search(context.Background(), SearchQuery{Query: "apple"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Data) != 1 || result.Data[0].FIGI != "BBG000BLNNH6" {
+		t.Fatalf("unexpected data: %+v", result.Data)
+	}
+	if result.Next != "abc123" {
+		t.Fatalf("expected Next=abc123, got %q", result.Next)
+	}
+}
+
+func TestClientSearchError(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/search", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"error":"invalid query"}`))
+	})
+	c := newV3TestClient(t, mux)
+
+	if _, err := c.Search(context.Background(), SearchQuery{}); err == nil || err.Error() != "invalid query" {
+		t.Fatalf("expected error %q, got %v", "invalid query", err)
+	}
+}
+
+func TestClientFilter(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/filter", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":[{"figi":"BBG000BLNNH6"}],"total":42}`))
+	})
+	c := newV3TestClient(t, mux)
+
+	result, err := c.Filter(context.Background(), FilterQuery{Query: "apple"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 42 {
+		t.Fatalf("expected Total=42, got %d", result.Total)
+	}
+}
+
+func TestClientFilterError(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/filter", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"error":"invalid filter"}`))
+	})
+	c := newV3TestClient(t, mux)
+
+	if _, err := c.Filter(context.Background(), FilterQuery{}); err == nil || err.Error() != "invalid filter" {
+		t.Fatalf("expected error %q, got %v", "invalid filter", err)
+	}
+}
+
+func TestClientMappingValues(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mapping/values/exchCode", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"values":["US","LN"]}`))
+	})
+	c := newV3TestClient(t, mux)
+
+	values, err := c.MappingValues(context.Background(), "exchCode")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 2 || values[0] != "US" || values[1] != "LN" {
+		t.Fatalf("unexpected values: %v", values)
+	}
+}
+
+func TestClientMappingValuesError(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mapping/values/exchCode", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	})
+	c := newV3TestClient(t, mux)
+
+	_, err := c.MappingValues(context.Background(), "exchCode")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("expected *APIError with status 500, got %v", err)
+	}
+}
+
+func TestClientValidateFieldsRejectsInvalidExchangeCode(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mapping/values/exchCode", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"values":["US","LN"]}`))
+	})
+	c := newV3TestClient(t, mux)
+
+	fr := &FIGIRequest{ExchangeCode: "NOT_REAL"}
+	if err := c.ValidateFields(context.Background(), fr); err == nil {
+		t.Fatal("expected an error for an invalid exchange code")
+	}
+}
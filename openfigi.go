@@ -3,18 +3,14 @@
 package openfigi
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"errors"
-	"fmt"
-	"io/ioutil"
-	"net/http"
 	"time"
-
-	"github.com/gomodule/redigo/redis"
 )
 
-const apiURL = "https://api.openfigi.com/v1/mapping"
+// apiURL is a var, not a const, so tests can point it at an
+// httptest.Server instead of the real API.
+var apiURL = "https://api.openfigi.com/v1/mapping"
 
 const defaultTimeout = 10 * time.Second
 
@@ -58,8 +54,12 @@ type FIGIRequest struct {
 	Currency     string `json:"currency,omitempty"`
 	MarketSector string `json:"marketSecDes,omitempty"`
 	// private vars
-	apiKey  string
-	timeout time.Duration
+	apiKey      string
+	timeout     time.Duration
+	retry       RetryPolicy
+	cache       Cache
+	cacheTTL    time.Duration
+	cacheTTLSet bool
 }
 
 // ValidIdentifiers is a list of valid openFIGI Request Identifiers.
@@ -116,128 +116,38 @@ func (fr *FIGIRequest) Exchange(exch string) {
 	fr.ExchangeCode = exch
 }
 
-// Do performs the openFIGI request. Although openFIGI supports up
-// to 5 queries per request, this implementation only supports 1.
-// Errors returned are one of the package wide errors or generic
-// http i/o or json parsing errors.
-func (fr *FIGIRequest) Do() ([]*FIGI, error) {
-	reqdata := []*FIGIRequest{fr}
-	js, err := json.Marshal(reqdata)
-	if err != nil {
-		return nil, err
-	}
-
-	cached, err := getCache(js)
-	if err != nil {
-		return nil, err
-	}
-	if cached != nil {
-		return cached, err
-	}
-
-	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(js))
-	req.Header.Set("Content-Type", "application/json")
-	if fr.apiKey != "" {
-		req.Header.Set("X-OPENFIGI-APIKEY", fr.apiKey)
-	}
-
-	client := &http.Client{
-		Timeout: fr.timeout,
-	}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return nil, ErrWrongStatus
-	}
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	data := []map[string][]*FIGI{}
-	err = json.Unmarshal(body, &data)
-	if err != nil {
-		// No data was found, check if we maybe have an error.
-		data := []map[string]string{}
-		err = json.Unmarshal(body, &data)
-		if err != nil {
-			return nil, err
-		}
-		if len(data) == 0 {
-			return nil, ErrAPIError
-		}
-		e := data[0]["error"]
-		if e == "No identifier found." {
-			// This is the most common error.
-			return nil, ErrNoIdentifierFound
-		} else if e != "" {
-			return nil, fmt.Errorf(data[0]["error"])
-		}
-		return nil, ErrAPIError
-	}
-	if len(data) == 0 {
-		return nil, ErrAPIError
-	}
-
-	if err := setCache(js, data[0]["data"]); err != nil {
-		return nil, ErrCacheError
-	}
-	return data[0]["data"], nil
+// Retry overrides the RetryPolicy used by DoContext for this request,
+// taking precedence over DefaultClient's.
+func (fr *FIGIRequest) Retry(policy RetryPolicy) {
+	fr.retry = policy
 }
 
-var rpool *redis.Pool
-
-// RedisCache sets up Redis to use as a cache for openFIGI data.
-// If you don't call this on startup, no caching is performed.
-func RedisCache(addr string) error {
-	rpool = &redis.Pool{
-		MaxIdle:     5,
-		IdleTimeout: 240 * time.Second,
-		Dial: func() (redis.Conn, error) {
-			return redis.Dial("tcp", addr)
-		},
-	}
-	return nil
+// WithCache sets the Cache used to store and look up this request's
+// result, taking precedence over DefaultClient's. Pass nil to fall back
+// to DefaultClient's cache.
+func (fr *FIGIRequest) WithCache(c Cache) {
+	fr.cache = c
 }
 
-func getCache(key []byte) ([]*FIGI, error) {
-	if rpool == nil {
-		return nil, nil
-	}
-	c := rpool.Get()
-	defer c.Close() // nolint: errcheck
-	res, err := c.Do("GET", string(key))
-	if err != nil {
-		return nil, err
-	}
-	if res == nil {
-		return nil, nil
-	}
-	b, err := redis.Bytes(res, err)
-	if err != nil {
-		return nil, err
-	}
-	data := []*FIGI{}
-	err = json.Unmarshal(b, &data)
-	return data, err
+// CacheTTL overrides how long this request's result is kept in the
+// cache, taking precedence over DefaultClient's. A ttl of 0 means no
+// expiry, per the Cache interface; it does not fall back to
+// DefaultClient's ttl the way an unset CacheTTL does.
+func (fr *FIGIRequest) CacheTTL(ttl time.Duration) {
+	fr.cacheTTL = ttl
+	fr.cacheTTLSet = true
 }
 
-func setCache(key []byte, data []*FIGI) error {
-	if rpool == nil {
-		return nil
-	}
-	c := rpool.Get()
-	defer c.Close() // nolint: errcheck
+// Do performs the openFIGI request using DefaultClient. Although
+// openFIGI supports up to 10 queries per request, this implementation
+// only supports 1; use Batch to submit more. Errors returned are one of
+// the package wide errors, an *APIError, or a generic http i/o or json
+// parsing error. It is equivalent to DoContext(context.Background()).
+func (fr *FIGIRequest) Do() ([]*FIGI, error) {
+	return fr.DoContext(context.Background())
+}
 
-	js, err := json.Marshal(data)
-	if err != nil {
-		return err
-	}
-	_, err = c.Do("SET", string(key), string(js))
-	return err
+// DoContext is like Do but honors ctx cancellation and deadlines.
+func (fr *FIGIRequest) DoContext(ctx context.Context) ([]*FIGI, error) {
+	return DefaultClient.do(ctx, fr)
 }
@@ -0,0 +1,182 @@
+package openfigi
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Documented OpenFIGI per-minute quotas, used as defaults when a Client
+// or Batch isn't given an explicit WithRateLimit/RateLimit call: the
+// keyed-tier values apply once an API key is set, otherwise the
+// (much stricter) free-tier values apply.
+const (
+	freeTierRequestsPerMinute  = 25
+	freeTierJobsPerMinute      = 25
+	keyedTierRequestsPerMinute = 250
+	keyedTierJobsPerMinute     = 100000
+)
+
+// RateLimiter is a token-bucket limiter covering both of the dimensions
+// OpenFIGI enforces: requests/minute and jobs/minute. Wait blocks until
+// both a request token and the requested number of job tokens are
+// available, or ctx is done.
+type RateLimiter struct {
+	mu sync.Mutex
+
+	requests tokenBucket
+	jobs     tokenBucket
+
+	// OnThrottle, if set, is called each time Wait has to block, with the
+	// duration it is about to wait before trying again. Useful for
+	// observing queueing under load.
+	OnThrottle func(wait time.Duration)
+}
+
+// NewRateLimiter creates a RateLimiter allowing up to requestsPerMinute
+// requests and jobsPerMinute mapping jobs per minute.
+func NewRateLimiter(requestsPerMinute, jobsPerMinute int) *RateLimiter {
+	now := time.Now()
+	return &RateLimiter{
+		requests: newTokenBucket(requestsPerMinute, now),
+		jobs:     newTokenBucket(jobsPerMinute, now),
+	}
+}
+
+// Wait blocks until a request token and n job tokens are available, or
+// ctx is done. Call with n=1 for a single-job request, or the number of
+// jobs about to be submitted for a batch chunk.
+func (rl *RateLimiter) Wait(ctx context.Context, n int) error {
+	for {
+		wait := rl.reserve(n)
+		if wait == 0 {
+			return nil
+		}
+		if rl.OnThrottle != nil {
+			rl.OnThrottle(wait)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve refills both buckets, and if enough tokens of each are already
+// available, consumes them and returns 0. Otherwise it leaves the
+// buckets untouched and returns how long the caller should wait before
+// trying again.
+func (rl *RateLimiter) reserve(n int) time.Duration {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := time.Now()
+	rl.requests.refill(now)
+	rl.jobs.refill(now)
+
+	wait := rl.requests.waitDuration(1)
+	if w := rl.jobs.waitDuration(float64(n)); w > wait {
+		wait = w
+	}
+	if wait > 0 {
+		return wait
+	}
+
+	rl.requests.tokens--
+	rl.jobs.tokens -= float64(n)
+	return 0
+}
+
+// tokenBucket is a classic token bucket: it holds up to capacity tokens
+// and refills at refillRate tokens/second, lazily computed on refill.
+type tokenBucket struct {
+	capacity   float64
+	tokens     float64
+	refillRate float64 // tokens per second
+	last       time.Time
+}
+
+func newTokenBucket(perMinute int, now time.Time) tokenBucket {
+	capacity := float64(perMinute)
+	return tokenBucket{
+		capacity:   capacity,
+		tokens:     capacity,
+		refillRate: capacity / 60,
+		last:       now,
+	}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	b.tokens += elapsed * b.refillRate
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.last = now
+}
+
+// waitDuration returns how long to wait for n tokens to become
+// available, given the bucket's current (already refilled) state.
+func (b *tokenBucket) waitDuration(n float64) time.Duration {
+	if b.tokens >= n {
+		return 0
+	}
+	if b.refillRate <= 0 {
+		return 0
+	}
+	deficit := n - b.tokens
+	return time.Duration(deficit / b.refillRate * float64(time.Second))
+}
+
+// defaultFreeLimiter and defaultKeyedLimiter are the package-wide
+// RateLimiters matching OpenFIGI's documented free-tier and keyed-tier
+// quotas. Client and Batch share these when they aren't given an
+// explicit RateLimiter, so the per-minute quota is actually enforced
+// across calls instead of each call getting a freshly topped-up bucket.
+var (
+	defaultFreeLimiter  = NewRateLimiter(freeTierRequestsPerMinute, freeTierJobsPerMinute)
+	defaultKeyedLimiter = NewRateLimiter(keyedTierRequestsPerMinute, keyedTierJobsPerMinute)
+)
+
+// defaultRateLimiter returns the shared free-tier or keyed-tier limiter
+// depending on whether an API key is set. Call it per-request with the
+// key that request will actually use, rather than caching its result,
+// so a key resolved at request time (e.g. FIGIRequest.APIKey overriding
+// a keyless Client) picks the right tier.
+func defaultRateLimiter(apiKey string) *RateLimiter {
+	if apiKey != "" {
+		return defaultKeyedLimiter
+	}
+	return defaultFreeLimiter
+}
+
+// waitForCapacity waits on limiter for n tokens, unless limiter is nil.
+// If onThrottle is set, it's called with the wall-clock time Wait
+// actually blocked for. This is used instead of limiter.OnThrottle
+// directly because limiter may be one of the shared default*Limiter
+// instances above, and setting its OnThrottle field would clobber
+// every other Client/Batch sharing it.
+func waitForCapacity(ctx context.Context, limiter *RateLimiter, n int, onThrottle func(time.Duration)) error {
+	if limiter == nil {
+		return nil
+	}
+	if onThrottle == nil {
+		return limiter.Wait(ctx, n)
+	}
+	if limiter.reserve(n) == 0 {
+		return nil
+	}
+	start := time.Now()
+	if err := limiter.Wait(ctx, n); err != nil {
+		return err
+	}
+	onThrottle(time.Since(start))
+	return nil
+}
@@ -0,0 +1,43 @@
+package openfigi
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterBlocksUntilTokensAvailable(t *testing.T) {
+	rl := NewRateLimiter(60, 60) // 1 token/sec on each dimension
+	ctx := context.Background()
+
+	// Drain the bucket.
+	for i := 0; i < 60; i++ {
+		if err := rl.Wait(ctx, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var throttled time.Duration
+	rl.OnThrottle = func(d time.Duration) { throttled = d }
+
+	deadline, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if err := rl.Wait(deadline, 1); err == nil {
+		t.Fatal("expected Wait to block past the context deadline")
+	}
+	if throttled == 0 {
+		t.Fatal("expected OnThrottle to be called")
+	}
+}
+
+func TestDefaultRateLimiterTiers(t *testing.T) {
+	free := defaultRateLimiter("")
+	if free.requests.capacity != freeTierRequestsPerMinute {
+		t.Fatalf("expected free-tier capacity %v, got %v", freeTierRequestsPerMinute, free.requests.capacity)
+	}
+
+	keyed := defaultRateLimiter("some-key")
+	if keyed.requests.capacity != keyedTierRequestsPerMinute {
+		t.Fatalf("expected keyed-tier capacity %v, got %v", keyedTierRequestsPerMinute, keyed.requests.capacity)
+	}
+}
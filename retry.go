@@ -0,0 +1,101 @@
+package openfigi
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// APIError is returned when the openFIGI api responds with a non-200
+// status that DoContext can't otherwise turn into one of the package
+// wide sentinel errors. It carries the status code and response body so
+// callers can distinguish e.g. an auth failure from a rate limit or a
+// server error. APIError unwraps to ErrWrongStatus for back-compat with
+// errors.Is(err, openfigi.ErrWrongStatus).
+type APIError struct {
+	StatusCode int
+	Body       []byte
+}
+
+func (e *APIError) Error() string {
+	return "openfigi: " + ErrWrongStatus.Error() + ": " + strconv.Itoa(e.StatusCode) + ": " + string(e.Body)
+}
+
+// Unwrap makes APIError compatible with errors.Is(err, ErrWrongStatus).
+func (e *APIError) Unwrap() error {
+	return ErrWrongStatus
+}
+
+// RetryPolicy controls how DoContext retries requests that fail with a
+// 429 (rate limited) or 5xx response. The wait between attempts starts
+// at InitialInterval and doubles (times Multiplier) each attempt, capped
+// at MaxInterval, with uniform jitter of ±50% applied on top. A
+// Retry-After header on a 429 response takes precedence over the
+// computed interval. Retrying stops once MaxRetries or MaxElapsed is
+// exceeded, whichever comes first.
+type RetryPolicy struct {
+	MaxRetries      int
+	MaxElapsed      time.Duration
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+}
+
+// DefaultRetryPolicy is used by DoContext when a FIGIRequest has no
+// RetryPolicy configured.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries:      5,
+	MaxElapsed:      2 * time.Minute,
+	InitialInterval: 500 * time.Millisecond,
+	MaxInterval:     30 * time.Second,
+	Multiplier:      2,
+}
+
+// isRetryableStatus reports whether a response with the given status
+// code should be retried. Only 429 and 5xx are retryable; other 4xx
+// responses are treated as permanent failures.
+func isRetryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
+
+// nextBackoff returns the wait duration before retry attempt n (0-based),
+// honoring a Retry-After header when present.
+func (p RetryPolicy) nextBackoff(n int, retryAfter string) time.Duration {
+	if d, ok := parseRetryAfter(retryAfter); ok {
+		return d
+	}
+
+	interval := float64(p.InitialInterval)
+	for i := 0; i < n; i++ {
+		interval *= p.Multiplier
+		if interval > float64(p.MaxInterval) {
+			interval = float64(p.MaxInterval)
+			break
+		}
+	}
+
+	jitter := interval * 0.5
+	interval += (rand.Float64()*2 - 1) * jitter
+	if interval < 0 {
+		interval = 0
+	}
+	return time.Duration(interval)
+}
+
+// parseRetryAfter parses the Retry-After header, which is either a
+// number of seconds or an HTTP-date.
+func parseRetryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,66 @@
+package openfigi
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNextBackoffBounds(t *testing.T) {
+	policy := DefaultRetryPolicy
+	for attempt := 0; attempt < 8; attempt++ {
+		d := policy.nextBackoff(attempt, "")
+		if d < 0 {
+			t.Fatalf("attempt %d: backoff went negative: %v", attempt, d)
+		}
+
+		uncapped := float64(policy.InitialInterval)
+		for i := 0; i < attempt; i++ {
+			uncapped *= policy.Multiplier
+		}
+		capped := uncapped
+		if capped > float64(policy.MaxInterval) {
+			capped = float64(policy.MaxInterval)
+		}
+		if max := capped * 1.5; float64(d) > max {
+			t.Fatalf("attempt %d: backoff %v exceeds jittered max %v", attempt, d, time.Duration(max))
+		}
+	}
+}
+
+func TestNextBackoffHonorsRetryAfter(t *testing.T) {
+	policy := DefaultRetryPolicy
+	if d := policy.nextBackoff(0, "2"); d != 2*time.Second {
+		t.Fatalf("expected Retry-After to take precedence, got %v", d)
+	}
+}
+
+func TestParseRetryAfterSeconds(t *testing.T) {
+	d, ok := parseRetryAfter("5")
+	if !ok || d != 5*time.Second {
+		t.Fatalf("expected (5s, true), got (%v, %v)", d, ok)
+	}
+}
+
+func TestParseRetryAfterHTTPDate(t *testing.T) {
+	future := time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat)
+	d, ok := parseRetryAfter(future)
+	if !ok {
+		t.Fatal("expected HTTP-date Retry-After to parse")
+	}
+	if d <= 0 || d > 11*time.Second {
+		t.Fatalf("expected ~10s, got %v", d)
+	}
+}
+
+func TestParseRetryAfterInvalid(t *testing.T) {
+	if _, ok := parseRetryAfter(""); ok {
+		t.Fatal("expected empty Retry-After to not parse")
+	}
+	if _, ok := parseRetryAfter("not-a-date-or-seconds"); ok {
+		t.Fatal("expected garbage Retry-After to not parse")
+	}
+	if _, ok := parseRetryAfter(time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)); ok {
+		t.Fatal("expected a past HTTP-date to not parse as a positive wait")
+	}
+}